@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	kctl "k8s.io/kubernetes/pkg/kubectl"
+	kcmd "k8s.io/kubernetes/pkg/kubectl/cmd"
+	kcmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+
+	"github.com/openshift/origin/pkg/cmd/cli/describe"
+	"github.com/openshift/origin/pkg/cmd/util/clientcmd"
+)
+
+// NewCmdDescribe wraps the upstream "describe" command, adding history flags that only
+// apply when describing a DeploymentConfig: --history limits how many prior deployments
+// are shown, --history-status restricts them to a comma-separated list of statuses (e.g.
+// "Failed,Complete"), and --history-since restricts them to a recent time window (e.g.
+// "24h"). Describing any other resource, or describing a DeploymentConfig with none of
+// these flags set, falls straight through to the upstream command unchanged.
+func NewCmdDescribe(fullName string, f *clientcmd.Factory, out io.Writer) *cobra.Command {
+	cmd := kcmd.NewCmdDescribe(f.Factory, out)
+
+	var historyCount int
+	var historyStatus string
+	var historySince time.Duration
+	cmd.Flags().IntVar(&historyCount, "history", 0, "Number of prior deployments to show when describing a deployment config (0 uses the default)")
+	cmd.Flags().StringVar(&historyStatus, "history-status", "", "Comma-separated deployment statuses to include in a deployment config's history (e.g. Failed,Complete)")
+	cmd.Flags().DurationVar(&historySince, "history-since", 0, "Only include a deployment config's deployments created within this duration of now (e.g. 24h)")
+
+	upstreamRun := cmd.Run
+	cmd.Run = func(c *cobra.Command, args []string) {
+		resource, name, hasName := splitDescribeArgs(args)
+		if !hasName || !isDeploymentConfigResource(resource) || (historyCount == 0 && len(historyStatus) == 0 && historySince == 0) {
+			upstreamRun(c, args)
+			return
+		}
+
+		namespace, _, err := f.DefaultNamespace()
+		kcmdutil.CheckErr(err)
+		osClient, kubeClient, err := f.Clients()
+		kcmdutil.CheckErr(err)
+
+		d := describe.NewDeploymentConfigDescriber(osClient, kubeClient, nil)
+		opts := describe.ParseDeploymentHistoryOptions(historyCount, historyStatus, historySince)
+		settings := kctl.DescriberSettings{ShowEvents: kcmdutil.GetFlagBool(c, "show-events")}
+		output, err := d.DescribeWithHistory(namespace, name, settings, opts)
+		kcmdutil.CheckErr(err)
+		fmt.Fprint(out, output)
+	}
+
+	return cmd
+}
+
+// splitDescribeArgs pulls the resource type and (if given) name out of "oc describe"
+// positional args, which are either "type" or "type name".
+func splitDescribeArgs(args []string) (resource, name string, hasName bool) {
+	if len(args) == 0 {
+		return "", "", false
+	}
+	resource = args[0]
+	if len(args) > 1 {
+		return resource, args[1], true
+	}
+	return resource, "", false
+}
+
+// isDeploymentConfigResource reports whether resource refers to a DeploymentConfig,
+// under any of the type names "oc describe" accepts for it.
+func isDeploymentConfigResource(resource string) bool {
+	switch resource {
+	case "dc", "deploymentconfig", "deploymentconfigs":
+		return true
+	default:
+		return false
+	}
+}
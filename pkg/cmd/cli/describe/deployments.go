@@ -17,19 +17,16 @@ import (
 	kctl "k8s.io/kubernetes/pkg/kubectl"
 	"k8s.io/kubernetes/pkg/labels"
 
-	"github.com/openshift/origin/pkg/api/graph"
-	kubegraph "github.com/openshift/origin/pkg/api/kubegraph/nodes"
 	"github.com/openshift/origin/pkg/client"
 	deployapi "github.com/openshift/origin/pkg/deploy/api"
 	deployedges "github.com/openshift/origin/pkg/deploy/graph"
-	deploygraph "github.com/openshift/origin/pkg/deploy/graph/nodes"
 	deployutil "github.com/openshift/origin/pkg/deploy/util"
 	imageapi "github.com/openshift/origin/pkg/image/api"
 )
 
 const (
-	// maxDisplayDeployments is the number of deployments to show when describing
-	// deployment configuration.
+	// maxDisplayDeployments is the total number of deployment blocks (the latest plus
+	// history) to show by default when no --history flag is given.
 	maxDisplayDeployments = 3
 
 	// maxDisplayDeploymentsEvents is the number of events to display when
@@ -58,6 +55,12 @@ func NewDeploymentConfigDescriber(client client.Interface, kclient kclient.Inter
 
 // Describe returns the description of a DeploymentConfig
 func (d *DeploymentConfigDescriber) Describe(namespace, name string, settings kctl.DescriberSettings) (string, error) {
+	return d.DescribeWithHistory(namespace, name, settings, DeploymentHistoryOptions{})
+}
+
+// DescribeWithHistory returns the description of a DeploymentConfig, restricting the
+// "Deployment #N" history section according to historyOpts.
+func (d *DeploymentConfigDescriber) DescribeWithHistory(namespace, name string, settings kctl.DescriberSettings, historyOpts DeploymentHistoryOptions) (string, error) {
 	var deploymentConfig *deployapi.DeploymentConfig
 	if d.config != nil {
 		// If a deployment config is already provided use that.
@@ -101,20 +104,43 @@ func (d *DeploymentConfigDescriber) Describe(namespace, name string, settings kc
 		}
 		// We don't show the deployment history when running `oc rollback --dry-run`.
 		if d.config == nil {
-			deploymentsHistory, err := d.kubeClient.ReplicationControllers(namespace).List(kapi.ListOptions{LabelSelector: labels.Everything()})
+			deploymentsHistory, err := d.kubeClient.ReplicationControllers(namespace).List(kapi.ListOptions{LabelSelector: deployutil.ConfigSelector(deploymentConfig.Name)})
 			if err == nil {
 				sorted := deploymentsHistory.Items
 				sort.Sort(sort.Reverse(rcutils.OverlappingControllers(sorted)))
-				counter := 1
-				for _, item := range sorted {
-					if item.Name != deploymentName && deploymentConfig.Name == deployutil.DeploymentConfigNameFor(&item) {
-						header := fmt.Sprintf("Deployment #%d", deployutil.DeploymentVersionFor(&item))
-						printDeploymentRc(&item, d.kubeClient, out, header, false)
-						counter++
+
+				history := buildDeploymentHistory(deploymentConfig, sorted)
+				eligible := make([]relevantDeployment, 0, len(history))
+				for _, entry := range history {
+					if entry.RC.Name == deploymentName {
+						continue
+					}
+					eligible = append(eligible, entry)
+				}
+				eligible = filterDeploymentHistory(eligible, historyOpts)
+
+				// The latest deployment, printed above, already accounts for one of
+				// maxDisplayDeployments; the rest is available for history.
+				count := maxDisplayDeployments - 1
+				if historyOpts.Count > 0 {
+					count = historyOpts.Count
+				}
+				shown := eligible
+				if len(eligible) > count {
+					shown = eligible[:count]
+				}
+				for _, entry := range shown {
+					header := fmt.Sprintf("Deployment #%d", deployutil.DeploymentVersionFor(entry.RC))
+					if entry.Inactive {
+						header += " (inactive)"
 					}
-					if counter == maxDisplayDeployments {
-						break
+					if len(entry.Reason) > 0 {
+						header += ", " + entry.Reason
 					}
+					printDeploymentRc(entry.RC, d.kubeClient, out, header, false)
+				}
+				if remaining := len(eligible) - len(shown); remaining > 0 {
+					fmt.Fprintf(out, "\t... %d more\n", remaining)
 				}
 			}
 		}
@@ -376,13 +402,7 @@ func (d *LatestDeploymentsDescriber) Describe(namespace, name string) (string, e
 		}
 	}
 
-	g := graph.New()
-	dcNode := deploygraph.EnsureDeploymentConfigNode(g, config)
-	for i := range deployments {
-		kubegraph.EnsureReplicationControllerNode(g, &deployments[i])
-	}
-	deployedges.AddTriggerEdges(g, dcNode)
-	deployedges.AddDeploymentEdges(g, dcNode)
+	g, dcNode := buildDeploymentConfigGraph(config, deployments)
 	activeDeployment, inactiveDeployments := deployedges.RelevantDeployments(g, dcNode)
 
 	return tabbedString(func(out *tabwriter.Writer) error {
@@ -0,0 +1,104 @@
+package describe
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	ktestclient "k8s.io/kubernetes/pkg/client/unversioned/testclient"
+	kctl "k8s.io/kubernetes/pkg/kubectl"
+	"k8s.io/kubernetes/pkg/runtime"
+
+	"github.com/openshift/origin/pkg/client/testclient"
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
+	deployutil "github.com/openshift/origin/pkg/deploy/util"
+)
+
+// manyDeploymentsConfig returns a DeploymentConfig together with n ReplicationControllers
+// that all belong to it, named <name>-0 to <name>-(n-1), each created an hour after the
+// one before it. None carries a deployment version and the config is at LatestVersion 0,
+// so the graph finds no active deployment among them and buildDeploymentHistory returns
+// all of them.
+func manyDeploymentsConfig(name string, n int) (*deployapi.DeploymentConfig, []runtime.Object) {
+	config := &deployapi.DeploymentConfig{
+		ObjectMeta: kapi.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: deployapi.DeploymentConfigSpec{
+			Triggers: []deployapi.DeploymentTriggerPolicy{
+				{Type: deployapi.DeploymentTriggerOnConfigChange},
+			},
+		},
+	}
+
+	var deployments []runtime.Object
+	for i := 0; i < n; i++ {
+		created := time.Now().Add(time.Duration(i-n) * time.Hour)
+		rc := rcNamed(fmt.Sprintf("%s-%d", name, i), name, created, deployapi.DeploymentStatusComplete)
+		deployments = append(deployments, &rc)
+	}
+	return config, deployments
+}
+
+// TestDescribeWithHistoryDefaultCountStaysAtMaxDisplayDeployments drives the default,
+// zero-flags "oc describe dc" path through Describe with more eligible history entries
+// than maxDisplayDeployments, and asserts the total number of deployment blocks shown
+// (latest + history) stays at maxDisplayDeployments, not maxDisplayDeployments+1.
+func TestDescribeWithHistoryDefaultCountStaysAtMaxDisplayDeployments(t *testing.T) {
+	config, deployments := manyDeploymentsConfig("dc", maxDisplayDeployments+2)
+
+	osFake := testclient.NewSimpleFake(config)
+	kubeFake := ktestclient.NewSimpleFake(deployments...)
+
+	d := NewDeploymentConfigDescriber(osFake, kubeFake, nil)
+	output, err := d.Describe("ns", "dc", kctl.DescriberSettings{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// "dc-0" is the name deployutil.LatestDeploymentNameForConfig gives a config at
+	// LatestVersion 0, so it is shown as the latest block and left out of the history.
+	// The other maxDisplayDeployments+1 deployments are eligible history, capped at
+	// maxDisplayDeployments-1 by default, which leaves 2 of them summarized.
+	shown := strings.Count(output, "Deployment #")
+	if shown != maxDisplayDeployments {
+		t.Fatalf("expected the default cap to show %d deployments in all, got %d:\n%s", maxDisplayDeployments, shown, output)
+	}
+	if !strings.Contains(output, "... 2 more") {
+		t.Errorf("expected the remaining eligible deployments to be summarized, got:\n%s", output)
+	}
+}
+
+// TestDescribeFetchesOnlyTargetConfigHistory fakes a namespace with ReplicationControllers
+// spread across two deployment configs and asserts that Describe's history List call is
+// scoped to the target config via deployutil.ConfigSelector, so describing one config can
+// never be diluted by another config's deployments.
+func TestDescribeFetchesOnlyTargetConfigHistory(t *testing.T) {
+	const targetDC = "target"
+	config, targetDeployments := manyDeploymentsConfig(targetDC, 2)
+	otherConfig, otherDeployments := manyDeploymentsConfig("other", 2)
+
+	osFake := testclient.NewSimpleFake(config, otherConfig)
+	kubeFake := ktestclient.NewSimpleFake(append(targetDeployments, otherDeployments...)...)
+
+	d := NewDeploymentConfigDescriber(osFake, kubeFake, nil)
+	if _, err := d.Describe("ns", targetDC, kctl.DescriberSettings{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sawHistoryList bool
+	for _, action := range kubeFake.Actions() {
+		listAction, ok := action.(ktestclient.ListActionImpl)
+		if !ok || listAction.GetResource() != "replicationcontrollers" {
+			continue
+		}
+		sawHistoryList = true
+		selector := listAction.GetListRestrictions().Labels
+		if selector.String() != deployutil.ConfigSelector(targetDC).String() {
+			t.Errorf("expected the history List call to be scoped to %q via deployutil.ConfigSelector, got selector %q", targetDC, selector)
+		}
+	}
+	if !sawHistoryList {
+		t.Fatalf("expected Describe to issue a replicationcontrollers List call for the deployment history")
+	}
+}
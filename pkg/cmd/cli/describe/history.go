@@ -0,0 +1,146 @@
+package describe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/util/sets"
+
+	"github.com/openshift/origin/pkg/api/graph"
+	kubegraph "github.com/openshift/origin/pkg/api/kubegraph/nodes"
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
+	deployedges "github.com/openshift/origin/pkg/deploy/graph"
+	deploygraph "github.com/openshift/origin/pkg/deploy/graph/nodes"
+	deployutil "github.com/openshift/origin/pkg/deploy/util"
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+// DeploymentHistoryOptions controls which prior deployments DescribeWithHistory
+// includes in the "Deployment #N" history section of a DeploymentConfig description.
+type DeploymentHistoryOptions struct {
+	// Count is the maximum number of prior deployments to display. Zero means
+	// use the describer's default (maxDisplayDeployments-1, as the latest
+	// deployment takes up one of maxDisplayDeployments).
+	Count int
+	// Status, if non-empty, restricts history to deployments in one of these statuses.
+	Status []deployapi.DeploymentStatus
+	// Since, if non-zero, restricts history to deployments created within this
+	// duration of now.
+	Since time.Duration
+}
+
+// ParseDeploymentHistoryOptions builds a DeploymentHistoryOptions from the flag values
+// the "oc describe dc" command's --history, --history-status and --history-since flags
+// are bound to. statusCSV is a comma-separated list of deployapi.DeploymentStatus values,
+// e.g. "Failed,Complete"; an empty statusCSV leaves history unfiltered by status.
+func ParseDeploymentHistoryOptions(count int, statusCSV string, since time.Duration) DeploymentHistoryOptions {
+	opts := DeploymentHistoryOptions{Count: count, Since: since}
+	for _, s := range strings.Split(statusCSV, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			opts.Status = append(opts.Status, deployapi.DeploymentStatus(s))
+		}
+	}
+	return opts
+}
+
+// relevantDeployment is a ReplicationController that belongs to a DeploymentConfig's
+// trigger-relevant ancestry, decorated with why it's there and whether it's still
+// owned by a live config.
+type relevantDeployment struct {
+	RC       *kapi.ReplicationController
+	Inactive bool
+	Reason   string
+}
+
+// buildDeploymentConfigGraph builds a trigger-aware graph for config and its known
+// deployments, wiring config-change and image-change trigger edges so that
+// deployedges.RelevantDeployments can distinguish currently-relevant ancestry from
+// deployments orphaned by later trigger or config changes. Shared by
+// DeploymentConfigDescriber and LatestDeploymentsDescriber so both walk the same graph.
+func buildDeploymentConfigGraph(config *deployapi.DeploymentConfig, deployments []kapi.ReplicationController) (graph.Graph, *deploygraph.DeploymentConfigNode) {
+	g := graph.New()
+	dcNode := deploygraph.EnsureDeploymentConfigNode(g, config)
+	for i := range deployments {
+		kubegraph.EnsureReplicationControllerNode(g, &deployments[i])
+	}
+	deployedges.AddTriggerEdges(g, dcNode)
+	deployedges.AddDeploymentEdges(g, dcNode)
+	return g, dcNode
+}
+
+// buildDeploymentHistory runs deployments through the shared trigger-aware graph and
+// returns every deployment but the active one, each entry annotated with whether the
+// graph considers it orphaned (no longer reachable from the config's current trigger
+// chain) and why it was ever relevant.
+func buildDeploymentHistory(config *deployapi.DeploymentConfig, deployments []kapi.ReplicationController) []relevantDeployment {
+	g, dcNode := buildDeploymentConfigGraph(config, deployments)
+	activeNode, inactiveNodes := deployedges.RelevantDeployments(g, dcNode)
+
+	reason := triggerReason(config.Spec)
+	inactive := sets.NewString()
+	for _, node := range inactiveNodes {
+		inactive.Insert(node.ReplicationController.Name)
+	}
+
+	history := make([]relevantDeployment, 0, len(deployments))
+	for i := range deployments {
+		rc := &deployments[i]
+		if activeNode != nil && rc.Name == activeNode.ReplicationController.Name {
+			continue
+		}
+		history = append(history, relevantDeployment{
+			RC:       rc,
+			Inactive: inactive.Has(rc.Name),
+			Reason:   reason,
+		})
+	}
+	return history
+}
+
+// triggerReason derives a short human-readable explanation of why deployments for this
+// config's ancestry exist, e.g. "triggered by ImageStreamTag ruby:2.3".
+func triggerReason(spec deployapi.DeploymentConfigSpec) string {
+	for _, t := range spec.Triggers {
+		switch t.Type {
+		case deployapi.DeploymentTriggerOnImageChange:
+			if t.ImageChangeParams != nil && len(t.ImageChangeParams.From.Name) > 0 {
+				name, tag, _ := imageapi.SplitImageStreamTag(t.ImageChangeParams.From.Name)
+				return fmt.Sprintf("triggered by ImageStreamTag %s:%s", name, tag)
+			}
+		case deployapi.DeploymentTriggerOnConfigChange:
+			return "triggered by config change"
+		}
+	}
+	return ""
+}
+
+// filterDeploymentHistory narrows items to those matching opts.Status and opts.Since.
+// An empty DeploymentHistoryOptions is a no-op.
+func filterDeploymentHistory(items []relevantDeployment, opts DeploymentHistoryOptions) []relevantDeployment {
+	if len(opts.Status) == 0 && opts.Since == 0 {
+		return items
+	}
+
+	statuses := sets.NewString()
+	for _, status := range opts.Status {
+		statuses.Insert(string(status))
+	}
+	var since time.Time
+	if opts.Since > 0 {
+		since = time.Now().Add(-opts.Since)
+	}
+
+	filtered := make([]relevantDeployment, 0, len(items))
+	for _, item := range items {
+		if statuses.Len() > 0 && !statuses.Has(string(deployutil.DeploymentStatusFor(item.RC))) {
+			continue
+		}
+		if opts.Since > 0 && item.RC.CreationTimestamp.Time.Before(since) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
@@ -0,0 +1,171 @@
+package describe
+
+import (
+	"testing"
+	"time"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	unversionedapi "k8s.io/kubernetes/pkg/api/unversioned"
+
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
+)
+
+func rcNamed(name, dcName string, created time.Time, status deployapi.DeploymentStatus) kapi.ReplicationController {
+	return kapi.ReplicationController{
+		ObjectMeta: kapi.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: unversionedapi.NewTime(created),
+			Annotations: map[string]string{
+				deployapi.DeploymentConfigAnnotation: dcName,
+				deployapi.DeploymentStatusAnnotation: string(status),
+			},
+		},
+	}
+}
+
+func entryFor(rc kapi.ReplicationController) relevantDeployment {
+	return relevantDeployment{RC: &rc}
+}
+
+func TestFilterDeploymentHistoryNoOptions(t *testing.T) {
+	entries := []relevantDeployment{
+		entryFor(rcNamed("dc-1", "dc", time.Now(), deployapi.DeploymentStatusComplete)),
+		entryFor(rcNamed("dc-2", "dc", time.Now(), deployapi.DeploymentStatusFailed)),
+	}
+
+	filtered := filterDeploymentHistory(entries, DeploymentHistoryOptions{})
+	if len(filtered) != len(entries) {
+		t.Fatalf("expected no filtering with empty options, got %d items", len(filtered))
+	}
+}
+
+func TestFilterDeploymentHistoryByStatus(t *testing.T) {
+	entries := []relevantDeployment{
+		entryFor(rcNamed("dc-1", "dc", time.Now(), deployapi.DeploymentStatusComplete)),
+		entryFor(rcNamed("dc-2", "dc", time.Now(), deployapi.DeploymentStatusFailed)),
+		entryFor(rcNamed("dc-3", "dc", time.Now(), deployapi.DeploymentStatusFailed)),
+	}
+
+	filtered := filterDeploymentHistory(entries, DeploymentHistoryOptions{Status: []deployapi.DeploymentStatus{deployapi.DeploymentStatusFailed}})
+	if len(filtered) != 2 {
+		t.Fatalf("expected 2 failed deployments, got %d", len(filtered))
+	}
+	for _, entry := range filtered {
+		if entry.RC.Name == "dc-1" {
+			t.Errorf("did not expect complete deployment %q to survive the status filter", entry.RC.Name)
+		}
+	}
+}
+
+func TestFilterDeploymentHistoryBySince(t *testing.T) {
+	now := time.Now()
+	entries := []relevantDeployment{
+		entryFor(rcNamed("recent", "dc", now.Add(-time.Hour), deployapi.DeploymentStatusComplete)),
+		entryFor(rcNamed("stale", "dc", now.Add(-48*time.Hour), deployapi.DeploymentStatusComplete)),
+	}
+
+	filtered := filterDeploymentHistory(entries, DeploymentHistoryOptions{Since: 24 * time.Hour})
+	if len(filtered) != 1 || filtered[0].RC.Name != "recent" {
+		t.Fatalf("expected only the recent deployment to survive the since filter, got %#v", filtered)
+	}
+}
+
+func TestParseDeploymentHistoryOptions(t *testing.T) {
+	opts := ParseDeploymentHistoryOptions(5, "Failed, Complete", 24*time.Hour)
+	if opts.Count != 5 {
+		t.Errorf("expected Count to be 5, got %d", opts.Count)
+	}
+	if opts.Since != 24*time.Hour {
+		t.Errorf("expected Since to be 24h, got %v", opts.Since)
+	}
+	if len(opts.Status) != 2 || opts.Status[0] != deployapi.DeploymentStatusFailed || opts.Status[1] != deployapi.DeploymentStatusComplete {
+		t.Errorf("expected Status to be [Failed Complete], got %v", opts.Status)
+	}
+}
+
+func TestParseDeploymentHistoryOptionsNoStatus(t *testing.T) {
+	opts := ParseDeploymentHistoryOptions(0, "", 0)
+	if len(opts.Status) != 0 {
+		t.Errorf("expected no status filter for an empty CSV, got %v", opts.Status)
+	}
+}
+
+func TestTriggerReasonImageChange(t *testing.T) {
+	spec := deployapi.DeploymentConfigSpec{
+		Triggers: []deployapi.DeploymentTriggerPolicy{
+			{
+				Type: deployapi.DeploymentTriggerOnImageChange,
+				ImageChangeParams: &deployapi.DeploymentTriggerImageChangeParams{
+					From: kapi.ObjectReference{Name: "ruby:2.3"},
+				},
+			},
+		},
+	}
+
+	reason := triggerReason(spec)
+	if reason != "triggered by ImageStreamTag ruby:2.3" {
+		t.Errorf("expected an ImageStreamTag explanation, got %q", reason)
+	}
+}
+
+func TestTriggerReasonConfigChange(t *testing.T) {
+	spec := deployapi.DeploymentConfigSpec{
+		Triggers: []deployapi.DeploymentTriggerPolicy{
+			{Type: deployapi.DeploymentTriggerOnConfigChange},
+		},
+	}
+
+	reason := triggerReason(spec)
+	if reason != "triggered by config change" {
+		t.Errorf("expected a config change explanation, got %q", reason)
+	}
+}
+
+func TestTriggerReasonNone(t *testing.T) {
+	reason := triggerReason(deployapi.DeploymentConfigSpec{})
+	if len(reason) != 0 {
+		t.Errorf("expected no explanation when there are no triggers, got %q", reason)
+	}
+}
+
+// TestBuildDeploymentHistoryExcludesActiveDeployment uses deployments that all belong to
+// config, matching what deployments.go's ConfigSelector-filtered List call actually
+// produces: DeploymentConfigNameFor already equals config.Name for every one of them, so
+// that comparison can never distinguish active from orphaned. Only the most recent one -
+// the graph's active node - should be excluded from the history, and the rest carry the
+// config's trigger reason.
+func TestBuildDeploymentHistoryExcludesActiveDeployment(t *testing.T) {
+	config := &deployapi.DeploymentConfig{
+		ObjectMeta: kapi.ObjectMeta{Name: "dc", Namespace: "ns"},
+		Spec: deployapi.DeploymentConfigSpec{
+			Triggers: []deployapi.DeploymentTriggerPolicy{
+				{Type: deployapi.DeploymentTriggerOnConfigChange},
+			},
+		},
+		Status: deployapi.DeploymentConfigStatus{LatestVersion: 2},
+	}
+	deployments := []kapi.ReplicationController{
+		rcNamed("dc-1", "dc", time.Now().Add(-2*time.Hour), deployapi.DeploymentStatusComplete),
+		rcNamed("dc-2", "dc", time.Now().Add(-time.Hour), deployapi.DeploymentStatusComplete),
+	}
+	// Like the deployments a real List returns, each one lives in the config's namespace
+	// and carries its version, so the graph can tell that dc-2 is the latest.
+	for i := range deployments {
+		deployments[i].Namespace = "ns"
+	}
+	deployments[0].Annotations[deployapi.DeploymentVersionAnnotation] = "1"
+	deployments[1].Annotations[deployapi.DeploymentVersionAnnotation] = "2"
+
+	history := buildDeploymentHistory(config, deployments)
+	if len(history) != len(deployments)-1 {
+		t.Fatalf("expected the active deployment to be excluded from history, got %d entries", len(history))
+	}
+	for _, entry := range history {
+		if entry.Reason != "triggered by config change" {
+			t.Errorf("expected every history entry to carry the config's trigger reason, got %q", entry.Reason)
+		}
+		if entry.RC.Name == "dc-2" {
+			t.Errorf("expected the most recent deployment to be the active one and excluded from history")
+		}
+	}
+}
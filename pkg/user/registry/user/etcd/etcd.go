@@ -2,9 +2,11 @@ package etcd
 
 import (
 	"errors"
+	"fmt"
 
 	kapi "k8s.io/kubernetes/pkg/api"
 	kerrs "k8s.io/kubernetes/pkg/api/errors"
+	kclient "k8s.io/kubernetes/pkg/client/unversioned"
 	"k8s.io/kubernetes/pkg/fields"
 	"k8s.io/kubernetes/pkg/labels"
 	"k8s.io/kubernetes/pkg/registry/generic"
@@ -24,12 +26,38 @@ import (
 // rest implements a RESTStorage for users against etcd
 type REST struct {
 	registry.Store
+
+	// identityResolvers are consulted, in order, whenever the "~" alias is
+	// resolved to the authenticated user. The first resolver that returns
+	// ok=true wins; if none do, Get returns the persisted user or, where
+	// there is none, the bare virtual user.
+	identityResolvers []IdentityResolver
+}
+
+// IdentityResolver allows the "~" (self) lookup to be enriched or overridden
+// with information the authenticator already attached to the request
+// context, such as federated identity provider claims. Implementations are
+// consulted for both persisted and virtual (not-yet-persisted) principals.
+type IdentityResolver interface {
+	// Resolve returns a User for the self-lookup. For a principal with no
+	// persisted user it is returned in place of the bare virtual user; for a
+	// persisted user only its FullName, Groups and Annotations are overlaid
+	// (see mergeResolvedIdentity). ok is false if this resolver has no opinion
+	// about name, in which case the next resolver (or the default behavior) is used.
+	Resolve(ctx kapi.Context, name string, groups []string) (*api.User, bool, error)
 }
 
 const EtcdPrefix = "/users"
 
 // NewREST returns a RESTStorage object that will work against users
 func NewREST(optsGetter restoptions.Getter) (*REST, error) {
+	return NewRESTWithResolvers(optsGetter, NewExtraClaimsIdentityResolver())
+}
+
+// NewRESTWithResolvers returns a RESTStorage object that will work against
+// users, consulting the given IdentityResolvers when resolving the "~"
+// alias. Resolvers are tried in order.
+func NewRESTWithResolvers(optsGetter restoptions.Getter, resolvers ...IdentityResolver) (*REST, error) {
 
 	store := &registry.Store{
 		NewFunc:     func() runtime.Object { return &api.User{} },
@@ -56,31 +84,57 @@ func NewREST(optsGetter restoptions.Getter) (*REST, error) {
 		return nil, err
 	}
 
-	return &REST{*store}, nil
+	return &REST{Store: *store, identityResolvers: resolvers}, nil
+}
+
+// selfFromContext translates the "~" alias into the authenticated principal's name and
+// their non-virtual groups, as attached to ctx by the authentication layer.
+func selfFromContext(ctx kapi.Context) (name string, groups []string, err error) {
+	user, ok := kapi.UserFrom(ctx)
+	if !ok || user.GetName() == "" {
+		return "", nil, kerrs.NewForbidden(api.Resource("user"), "~", errors.New("requests to ~ must be authenticated"))
+	}
+
+	// remove the known virtual groups from the list if they are present
+	contextGroups := sets.NewString(user.GetGroups()...)
+	contextGroups.Delete(bootstrappolicy.UnauthenticatedGroup, bootstrappolicy.AuthenticatedGroup)
+
+	return user.GetName(), contextGroups.List(), nil
 }
 
 // Get retrieves the item from etcd.
 func (r *REST) Get(ctx kapi.Context, name string) (runtime.Object, error) {
 	// "~" means the currently authenticated user
 	if name == "~" {
-		user, ok := kapi.UserFrom(ctx)
-		if !ok || user.GetName() == "" {
-			return nil, kerrs.NewForbidden(api.Resource("user"), "~", errors.New("requests to ~ must be authenticated"))
+		name, groups, err := selfFromContext(ctx)
+		if err != nil {
+			return nil, err
 		}
-		name = user.GetName()
-
-		// remove the known virtual groups from the list if they are present
-		contextGroups := sets.NewString(user.GetGroups()...)
-		contextGroups.Delete(bootstrappolicy.UnauthenticatedGroup, bootstrappolicy.AuthenticatedGroup)
 
 		if ok, _ := validation.ValidateUserName(name, false); !ok {
-			// The user the authentication layer has identified cannot possibly be a persisted user
-			// Return an API representation of the virtual user
-			return &api.User{ObjectMeta: kapi.ObjectMeta{Name: name}, Groups: contextGroups.List()}, nil
+			// The user the authentication layer has identified cannot possibly be a persisted user.
+			// Give the resolvers a chance to materialize a richer User before falling back to the
+			// bare virtual representation.
+			if resolved, found, err := r.resolveIdentity(ctx, name, groups); err != nil {
+				return nil, err
+			} else if found {
+				return resolved, nil
+			}
+			return &api.User{ObjectMeta: kapi.ObjectMeta{Name: name}, Groups: groups}, nil
 		}
 
 		obj, err := r.Store.Get(ctx, name)
 		if err == nil {
+			// The persisted object exists; let the resolvers enrich it with attributes the
+			// authenticator attached to this request (e.g. federated claims). The resolver's
+			// FullName and Groups replace the persisted ones, even when empty, and its
+			// Annotations are added on top; it never replaces the persisted identity
+			// (Name, UID, ResourceVersion, Identities survive untouched).
+			if resolved, found, err := r.resolveIdentity(ctx, name, groups); err != nil {
+				return nil, err
+			} else if found {
+				return mergeResolvedIdentity(obj.(*api.User), resolved), nil
+			}
 			return obj, nil
 		}
 
@@ -88,7 +142,12 @@ func (r *REST) Get(ctx kapi.Context, name string) (runtime.Object, error) {
 			return nil, err
 		}
 
-		return &api.User{ObjectMeta: kapi.ObjectMeta{Name: name}, Groups: contextGroups.List()}, nil
+		if resolved, found, err := r.resolveIdentity(ctx, name, groups); err != nil {
+			return nil, err
+		} else if found {
+			return resolved, nil
+		}
+		return &api.User{ObjectMeta: kapi.ObjectMeta{Name: name}, Groups: groups}, nil
 	}
 
 	if ok, details := validation.ValidateUserName(name, false); !ok {
@@ -97,3 +156,101 @@ func (r *REST) Get(ctx kapi.Context, name string) (runtime.Object, error) {
 
 	return r.Store.Get(ctx, name)
 }
+
+// mergeResolvedIdentity overlays the attributes an IdentityResolver is allowed to set
+// (FullName, Groups and Annotations) onto a copy of the persisted user, leaving the
+// identity-establishing fields a resolver must never touch (Name, UID, ResourceVersion,
+// Identities) exactly as they are in etcd.
+func mergeResolvedIdentity(existing, resolved *api.User) *api.User {
+	merged := *existing
+	merged.FullName = resolved.FullName
+	merged.Groups = resolved.Groups
+	if len(resolved.Annotations) > 0 {
+		if merged.Annotations == nil {
+			merged.Annotations = map[string]string{}
+		}
+		for k, v := range resolved.Annotations {
+			merged.Annotations[k] = v
+		}
+	}
+	return &merged
+}
+
+// resolveIdentity consults the configured IdentityResolvers, in order, and returns the
+// result of the first one that has an opinion about name.
+func (r *REST) resolveIdentity(ctx kapi.Context, name string, groups []string) (*api.User, bool, error) {
+	for _, resolver := range r.identityResolvers {
+		resolved, ok, err := resolver.Resolve(ctx, name, groups)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return resolved, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Update updates the item in etcd. A literal "~" is resolved to the caller's canonical
+// name and restricted to the fields a self-patch is allowed to touch (Annotations,
+// FullName); every other update passes the full incoming object through, as it always
+// has, except that Name, UID and Identities can never be moved off what's already
+// persisted, by any caller, whether the request is a self-patch that reached Update with
+// its canonical name already resolved (the real apiserver PATCH flow always resolves "~"
+// during the Get that precedes Update) or an ordinary update to another user. Because
+// the canonical-name case re-reads the object to build the merge, both cases retry on
+// conflict.
+func (r *REST) Update(ctx kapi.Context, obj runtime.Object) (runtime.Object, bool, error) {
+	update, ok := obj.(*api.User)
+	if !ok {
+		return nil, false, kerrs.NewBadRequest(fmt.Sprintf("not a user: %#v", obj))
+	}
+
+	name := update.Name
+	selfPatch := name == "~"
+	if selfPatch {
+		var err error
+		name, _, err = selfFromContext(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if ok, details := validation.ValidateUserName(name, false); !ok {
+		return nil, false, kerrs.NewForbidden(api.Resource("user"), "~", fmt.Errorf("the authenticated user %q cannot be persisted: %s", name, details))
+	}
+
+	var result runtime.Object
+	var created bool
+	err := kclient.RetryOnConflict(kclient.DefaultBackoff, func() error {
+		existingObj, getErr := r.Store.Get(ctx, name)
+		if getErr != nil {
+			return getErr
+		}
+		existing := existingObj.(*api.User)
+
+		var merged *api.User
+		if selfPatch {
+			// A "~" self-patch may only touch the fields a user is allowed to change
+			// about themselves; everything else comes from the persisted object.
+			m := *existing
+			m.Annotations = update.Annotations
+			m.FullName = update.FullName
+			merged = &m
+		} else {
+			// Any other caller - an admin, an identity-provider mapper linking a new
+			// Identity, and so on - gets the full incoming object, Name/UID/Identities
+			// aside.
+			m := *update
+			m.Name = existing.Name
+			m.UID = existing.UID
+			m.Identities = existing.Identities
+			merged = &m
+		}
+
+		var updateErr error
+		result, created, updateErr = r.Store.Update(ctx, merged)
+		return updateErr
+	})
+	return result, created, err
+}
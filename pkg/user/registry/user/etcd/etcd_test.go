@@ -0,0 +1,367 @@
+package etcd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	kapi "k8s.io/kubernetes/pkg/api"
+	kerrs "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/registry/registrytest"
+
+	"github.com/openshift/origin/pkg/user/api"
+	"github.com/openshift/origin/pkg/util/restoptions"
+)
+
+func newTestREST(t *testing.T) *REST {
+	etcdStorage, _ := registrytest.NewEtcdStorage(t, "")
+	rest, err := NewREST(restoptions.NewSimpleGetter(etcdStorage))
+	if err != nil {
+		t.Fatalf("unexpected error creating REST: %v", err)
+	}
+	return rest
+}
+
+type fakeUserInfo struct {
+	name   string
+	groups []string
+	extra  map[string][]string
+}
+
+func (u *fakeUserInfo) GetName() string               { return u.name }
+func (u *fakeUserInfo) GetUID() string                { return "" }
+func (u *fakeUserInfo) GetGroups() []string           { return u.groups }
+func (u *fakeUserInfo) GetExtra() map[string][]string { return u.extra }
+
+func contextWithUser(info kapi.UserInfo) kapi.Context {
+	return kapi.WithUser(kapi.NewContext(), info)
+}
+
+type fakeResolver struct {
+	user *api.User
+	ok   bool
+	err  error
+}
+
+func (r *fakeResolver) Resolve(ctx kapi.Context, name string, groups []string) (*api.User, bool, error) {
+	return r.user, r.ok, r.err
+}
+
+func TestGetSelfVirtualUserNoResolvers(t *testing.T) {
+	r := &REST{}
+	ctx := contextWithUser(&fakeUserInfo{name: "system:anonymous"})
+
+	obj, err := r.Get(ctx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := obj.(*api.User)
+	if user.Name != "system:anonymous" {
+		t.Errorf("expected virtual user name to be system:anonymous, got %q", user.Name)
+	}
+}
+
+func TestGetSelfVirtualUserWithResolver(t *testing.T) {
+	resolved := &api.User{ObjectMeta: kapi.ObjectMeta{Name: "system:anonymous"}, FullName: "Federated User"}
+	r := &REST{identityResolvers: []IdentityResolver{&fakeResolver{user: resolved, ok: true}}}
+	ctx := contextWithUser(&fakeUserInfo{name: "system:anonymous"})
+
+	obj, err := r.Get(ctx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj.(*api.User).FullName != "Federated User" {
+		t.Errorf("expected resolver result to be returned, got %#v", obj)
+	}
+}
+
+func TestGetSelfResolverDeclines(t *testing.T) {
+	r := &REST{identityResolvers: []IdentityResolver{&fakeResolver{ok: false}}}
+	ctx := contextWithUser(&fakeUserInfo{name: "system:anonymous", groups: []string{"system:authenticated"}})
+
+	obj, err := r.Get(ctx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj.(*api.User).Name != "system:anonymous" {
+		t.Errorf("expected fallback virtual user, got %#v", obj)
+	}
+}
+
+func TestGetSelfResolverError(t *testing.T) {
+	boom := errors.New("boom")
+	r := &REST{identityResolvers: []IdentityResolver{&fakeResolver{err: boom}}}
+	ctx := contextWithUser(&fakeUserInfo{name: "system:anonymous"})
+
+	if _, err := r.Get(ctx, "~"); err != boom {
+		t.Errorf("expected resolver error to propagate, got %v", err)
+	}
+}
+
+func TestGetSelfPersistedUserResolverDoesNotOverrideIdentity(t *testing.T) {
+	r := newTestREST(t)
+	ctx := kapi.NewDefaultContext()
+
+	created, err := r.Create(ctx, &api.User{
+		ObjectMeta: kapi.ObjectMeta{Name: "bob"},
+		Identities: []string{"idp:bob"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	persisted := created.(*api.User)
+
+	resolved := &api.User{ObjectMeta: kapi.ObjectMeta{Name: "bob"}, FullName: "Federated Bob"}
+	r.identityResolvers = []IdentityResolver{&fakeResolver{user: resolved, ok: true}}
+	selfCtx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	obj, err := r.Get(selfCtx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := obj.(*api.User)
+	if user.FullName != "Federated Bob" {
+		t.Errorf("expected resolver to populate FullName, got %q", user.FullName)
+	}
+	if user.UID != persisted.UID {
+		t.Errorf("expected UID to survive resolver enrichment, got %q want %q", user.UID, persisted.UID)
+	}
+	if user.ResourceVersion != persisted.ResourceVersion {
+		t.Errorf("expected ResourceVersion to survive resolver enrichment, got %q want %q", user.ResourceVersion, persisted.ResourceVersion)
+	}
+	if len(user.Identities) != 1 || user.Identities[0] != "idp:bob" {
+		t.Errorf("expected Identities to survive resolver enrichment, got %v", user.Identities)
+	}
+}
+
+// TestGetSelfValidNameNeverPersistedFallsBackToResolver covers the third branch of
+// Get's "~" handling: name passes ValidateUserName but has never been created, so
+// r.Store.Get returns NotFound and Get falls back to the resolvers (and, if none match,
+// the bare virtual representation).
+func TestGetSelfValidNameNeverPersistedFallsBackToResolver(t *testing.T) {
+	r := newTestREST(t)
+	resolved := &api.User{ObjectMeta: kapi.ObjectMeta{Name: "bob"}, FullName: "Federated Bob"}
+	r.identityResolvers = []IdentityResolver{&fakeResolver{user: resolved, ok: true}}
+	ctx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	obj, err := r.Get(ctx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj.(*api.User).FullName != "Federated Bob" {
+		t.Errorf("expected the resolver's result for a valid-but-never-persisted name, got %#v", obj)
+	}
+}
+
+func TestGetSelfValidNameNeverPersistedNoResolverFallsBackToVirtualUser(t *testing.T) {
+	r := newTestREST(t)
+	ctx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	obj, err := r.Get(ctx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := obj.(*api.User)
+	if user.Name != "bob" {
+		t.Errorf("expected the virtual user name to be bob, got %q", user.Name)
+	}
+}
+
+func TestUpdateSelfRejectsVirtualUser(t *testing.T) {
+	r := &REST{}
+	ctx := contextWithUser(&fakeUserInfo{name: "system:anonymous"})
+
+	_, _, err := r.Update(ctx, &api.User{ObjectMeta: kapi.ObjectMeta{Name: "~"}, FullName: "New Name"})
+	if err == nil {
+		t.Fatal("expected an error updating a virtual user via ~")
+	}
+}
+
+// TestUpdateByNamePassesFullObjectThroughExceptIdentity exercises an ordinary update
+// addressed by canonical name (an admin labeling a User, an identity-provider mapper
+// linking a new Identity on login). It must not be restricted to the "~" self-patch
+// allowlist: every field the caller sent should come through, except Name, UID and
+// Identities, which always come from the persisted object.
+func TestUpdateByNamePassesFullObjectThroughExceptIdentity(t *testing.T) {
+	r := newTestREST(t)
+	ctx := kapi.NewDefaultContext()
+
+	created, err := r.Create(ctx, &api.User{
+		ObjectMeta: kapi.ObjectMeta{Name: "bob"},
+		Identities: []string{"idp:bob"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	persisted := created.(*api.User)
+
+	update := &api.User{
+		ObjectMeta: kapi.ObjectMeta{
+			Name:   "bob",
+			Labels: map[string]string{"team": "payments"},
+		},
+		FullName:   "Bob Example",
+		Groups:     []string{"admins"},
+		Identities: []string{"stolen"},
+	}
+
+	result, _, err := r.Update(ctx, update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated := result.(*api.User)
+	if updated.Labels["team"] != "payments" {
+		t.Errorf("expected Labels to pass through for a non-self update, got %#v", updated.Labels)
+	}
+	if len(updated.Groups) != 1 || updated.Groups[0] != "admins" {
+		t.Errorf("expected Groups to pass through for a non-self update, got %v", updated.Groups)
+	}
+	if updated.FullName != "Bob Example" {
+		t.Errorf("expected FullName to pass through, got %q", updated.FullName)
+	}
+	if len(updated.Identities) != 1 || updated.Identities[0] != "idp:bob" {
+		t.Errorf("expected Identities to remain immutable for a non-self update, got %v", updated.Identities)
+	}
+	if updated.UID != persisted.UID {
+		t.Errorf("expected UID to remain immutable, got %q want %q", updated.UID, persisted.UID)
+	}
+}
+
+// TestUpdateRejectsUnresolvableNameRegardlessOfLiteralTilde covers the case a "~" Get
+// returns a virtual user whose Name is already the caller's raw, unpersistable name (not
+// the literal "~") before it's ever handed to Update - the same way the real PATCH flow
+// would present it.
+func TestUpdateRejectsUnresolvableNameRegardlessOfLiteralTilde(t *testing.T) {
+	r := &REST{}
+	ctx := kapi.NewDefaultContext()
+
+	_, _, err := r.Update(ctx, &api.User{ObjectMeta: kapi.ObjectMeta{Name: "system:anonymous"}, FullName: "New Name"})
+	if err == nil {
+		t.Fatal("expected an error updating an unpersistable virtual user name")
+	}
+	if !kerrs.IsForbidden(err) {
+		t.Errorf("expected a Forbidden error, got %v (%T)", err, err)
+	}
+}
+
+func TestUpdateSelfConcurrentPatch(t *testing.T) {
+	r := newTestREST(t)
+	ctx := kapi.NewDefaultContext()
+
+	if _, err := r.Create(ctx, &api.User{ObjectMeta: kapi.ObjectMeta{Name: "bob"}}); err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	selfCtx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for _, fullName := range []string{"Bob One", "Bob Two"} {
+		wg.Add(1)
+		go func(fullName string) {
+			defer wg.Done()
+			_, _, err := r.Update(selfCtx, &api.User{ObjectMeta: kapi.ObjectMeta{Name: "~"}, FullName: fullName})
+			errs <- err
+		}(fullName)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected concurrent self-patches to retry through conflicts, got: %v", err)
+		}
+	}
+
+	obj, err := r.Get(selfCtx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error re-reading self: %v", err)
+	}
+	user := obj.(*api.User)
+	if user.FullName != "Bob One" && user.FullName != "Bob Two" {
+		t.Errorf("expected one of the concurrent patches to win, got %q", user.FullName)
+	}
+}
+
+// TestUpdateAfterSelfGetPreservesIdentity mirrors the real PATCH flow: the framework
+// calls Get(ctx, "~") to fetch the current object (already resolved to its canonical
+// name, never "~"), merges the patch into it, then calls Update with that merged
+// object. It must not be possible to reach Update with a literal Name of "~" and still
+// have the identity guard apply only then.
+func TestUpdateAfterSelfGetPreservesIdentity(t *testing.T) {
+	r := newTestREST(t)
+	ctx := kapi.NewDefaultContext()
+
+	if _, err := r.Create(ctx, &api.User{
+		ObjectMeta: kapi.ObjectMeta{Name: "bob"},
+		Identities: []string{"idp:bob"},
+	}); err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	selfCtx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	obj, err := r.Get(selfCtx, "~")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	self := obj.(*api.User)
+	if self.Name != "bob" {
+		t.Fatalf("expected Get(~) to resolve to the canonical name before Update ever sees it, got %q", self.Name)
+	}
+
+	// A hostile or buggy client tries to smuggle an Identities change in on top of an
+	// otherwise-legitimate FullName patch.
+	self.FullName = "New Name"
+	self.Identities = []string{"stolen"}
+
+	result, _, err := r.Update(selfCtx, self)
+	if err != nil {
+		t.Fatalf("unexpected error updating through the realistic Get+Update path: %v", err)
+	}
+	updated := result.(*api.User)
+	if updated.FullName != "New Name" {
+		t.Errorf("expected FullName to be updated, got %q", updated.FullName)
+	}
+	if len(updated.Identities) != 1 || updated.Identities[0] != "idp:bob" {
+		t.Errorf("expected Identities to remain untouched by an update whose Name is not literally ~, got %v", updated.Identities)
+	}
+}
+
+func TestExtraClaimsIdentityResolverNoExtra(t *testing.T) {
+	resolver := NewExtraClaimsIdentityResolver()
+	ctx := contextWithUser(&fakeUserInfo{name: "bob"})
+
+	_, ok, err := resolver.Resolve(ctx, "bob", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Errorf("expected resolver to decline when no extra claims are present")
+	}
+}
+
+func TestExtraClaimsIdentityResolverEnriches(t *testing.T) {
+	resolver := NewExtraClaimsIdentityResolver()
+	ctx := contextWithUser(&fakeUserInfo{
+		name: "bob",
+		extra: map[string][]string{
+			ExtraKeyFullName: {"Bob Example"},
+			ExtraKeyEmail:    {"bob@example.com"},
+		},
+	})
+
+	user, ok, err := resolver.Resolve(ctx, "bob", []string{"idp-admins"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected resolver to resolve an enriched user")
+	}
+	if user.FullName != "Bob Example" {
+		t.Errorf("expected full name to be populated, got %q", user.FullName)
+	}
+	if user.Annotations[AnnotationEmail] != "bob@example.com" {
+		t.Errorf("expected email annotation to be populated, got %#v", user.Annotations)
+	}
+}
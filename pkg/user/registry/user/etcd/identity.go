@@ -0,0 +1,69 @@
+package etcd
+
+import (
+	kapi "k8s.io/kubernetes/pkg/api"
+
+	"github.com/openshift/origin/pkg/user/api"
+)
+
+const (
+	// ExtraKeyFullName is the key under which an authenticator may stash the
+	// federated principal's display name (e.g. an OIDC "name" claim or a SAML
+	// DisplayName attribute) in kapi.UserInfo.GetExtra().
+	ExtraKeyFullName = "user.openshift.io/full-name"
+
+	// ExtraKeyEmail is the key under which an authenticator may stash the
+	// federated principal's email address.
+	ExtraKeyEmail = "user.openshift.io/email"
+
+	// AnnotationEmail is the annotation the extra claims resolver uses to
+	// surface a federated email address on the returned User, since the
+	// internal User type has no dedicated field for it.
+	AnnotationEmail = "user.openshift.io/email"
+)
+
+// extraClaimsIdentityResolver is the built-in IdentityResolver that materializes
+// a richer User from the upstream provider claims the authenticator layer
+// already attached to the request context via kapi.UserInfo.GetExtra(). It
+// never errors and never persists anything; it only shapes the object
+// returned from a "~" lookup.
+type extraClaimsIdentityResolver struct{}
+
+// NewExtraClaimsIdentityResolver returns an IdentityResolver that enriches the
+// "~" lookup result with OIDC/SAML claims found in the request's extra user info.
+func NewExtraClaimsIdentityResolver() IdentityResolver {
+	return extraClaimsIdentityResolver{}
+}
+
+func (extraClaimsIdentityResolver) Resolve(ctx kapi.Context, name string, groups []string) (*api.User, bool, error) {
+	info, ok := kapi.UserFrom(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	extra := info.GetExtra()
+	fullName := firstExtraValue(extra, ExtraKeyFullName)
+	email := firstExtraValue(extra, ExtraKeyEmail)
+	if len(fullName) == 0 && len(email) == 0 {
+		// Nothing federated to add; defer to the default behavior.
+		return nil, false, nil
+	}
+
+	user := &api.User{
+		ObjectMeta: kapi.ObjectMeta{Name: name},
+		FullName:   fullName,
+		Groups:     groups,
+	}
+	if len(email) > 0 {
+		user.Annotations = map[string]string{AnnotationEmail: email}
+	}
+	return user, true, nil
+}
+
+func firstExtraValue(extra map[string][]string, key string) string {
+	values, ok := extra[key]
+	if !ok || len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}